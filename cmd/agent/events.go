@@ -0,0 +1,66 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/ngaut/logging"
+	"github.com/reborndb/reborn/pkg/events"
+)
+
+// eventRing backs GET /events?since=<id>; it's always part of
+// eventPoster so that endpoint works whether or not a NATS (or other)
+// bus is also configured.
+var eventRing = events.NewRingPoster(1024)
+
+// eventPoster is where process lifecycle events get published. It's
+// just eventRing for now; fanning out to events.NATSPoster as well
+// (via events.MultiPoster) needs agent startup config we don't have
+// yet to supply a NATS connection.
+var eventPoster events.Poster = eventRing
+
+func postEvent(ev events.Event) {
+	ev.Host = hostname()
+	ev.Time = time.Now()
+	if err := eventPoster.Post(ev); err != nil {
+		log.Errorf("post event %s for %s err %v", ev.Type, ev.ProcID, err)
+	}
+}
+
+func newProcEvent(typ events.Type, p *process) events.Event {
+	return events.Event{
+		Type:     typ,
+		ProcID:   p.ID,
+		ProcType: p.Type,
+		Pid:      p.Pid,
+	}
+}
+
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return h
+}
+
+func init() {
+	http.HandleFunc("/events", handleEvents)
+}
+
+// handleEvents handles GET /events?since=<id>, returning every buffered
+// event newer than id so a client can resume a stream after a
+// reconnect without duplicate events. Events that have already fallen
+// out of eventRing are omitted, so a client that was away for more
+// than its capacity sees a gap.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	json.NewEncoder(w).Encode(eventRing.Since(since))
+}
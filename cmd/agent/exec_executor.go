@@ -0,0 +1,147 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/mitchellh/go-ps"
+	log "github.com/ngaut/logging"
+	"github.com/reborndb/reborn/pkg/events"
+	"github.com/reborndb/reborn/pkg/system"
+)
+
+// execExecutor runs the managed process as a plain fork/exec on the
+// host, via the reborn-shim wrapper. It's the default Executor and the
+// only one that existed before the oci executor.
+type execExecutor struct{}
+
+func (execExecutor) Start(p *process) error {
+	return errors.Trace(p.startShim(p.Cmd, p.Args))
+}
+
+func (execExecutor) Signal(p *process, sig syscall.Signal) error {
+	return p.Signal(sig)
+}
+
+func (execExecutor) Wait(p *process) error {
+	for {
+		alive, err := p.executor().CheckAlive(p)
+		if err != nil {
+			return errors.Trace(err)
+		} else if !alive {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (execExecutor) CheckAlive(p *process) (bool, error) {
+	proc, err := ps.FindProcess(p.Pid)
+	if err != nil {
+		return false, errors.Trace(err)
+	} else if proc == nil {
+		// proc is not alive
+		return false, nil
+	}
+
+	start, err := system.ReadStartTime(p.Pid)
+	if errors.Cause(err) == system.ErrUnsupported {
+		// no reliable way to read start time on this platform (e.g.
+		// Windows), fall back to the old executable-name check.
+		if strings.Contains(proc.Executable(), p.Cmd) {
+			return true, nil
+		}
+		log.Warningf("pid %d exits, but exeutable name is %s, not %s", p.Pid, proc.Executable(), p.Cmd)
+		return false, nil
+	} else if err != nil {
+		return false, errors.Trace(err)
+	}
+
+	if p.StartTime != 0 && start != p.StartTime {
+		// pid has been recycled by some other program since we last
+		// recorded its start time, the process we were tracking is gone
+		log.Warningf("pid %d start time changed from %d to %d, process was replaced", p.Pid, p.StartTime, start)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (execExecutor) Stop(p *process) error {
+	b, err := p.executor().CheckAlive(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	defer p.clear()
+
+	if !b {
+		return nil
+	}
+
+	defer func() {
+		ev := newProcEvent(events.TypeProcessExited, p)
+		if st, err := p.readState(); err == nil {
+			ev.ExitCode, ev.Signal, ev.StartTime, ev.FinishTime = st.ExitCode, st.Signal, st.StartTime, time.Now()
+			if st.OOMKilled {
+				ev.Type = events.TypeProcessOOM
+			}
+		}
+		postEvent(ev)
+	}()
+
+	if p.stopFunc != nil {
+		// stopFunc replaces the graceful signal below with some other
+		// caller-supplied shutdown request; we still wait out the grace
+		// period and escalate to SIGKILL the same way.
+		if err := p.stopFunc(p); err != nil {
+			log.Errorf("stop %d (%s) err %v, send kill signal", p.Pid, p.Type, err)
+			p.killHard()
+			return nil
+		}
+	} else {
+		graceSig := p.GraceSignal
+		if graceSig == 0 {
+			graceSig = syscall.SIGTERM
+		}
+
+		if err := p.Signal(graceSig); err != nil {
+			log.Errorf("signal %d (%s) err %v, send kill signal", p.Pid, p.Type, err)
+			p.killHard()
+			return nil
+		}
+	}
+
+	gracePeriod := p.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultGracePeriod
+	}
+
+	deadline := time.Now().Add(gracePeriod)
+	for time.Now().Before(deadline) {
+		if alive, err := p.executor().CheckAlive(p); err != nil || !alive {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	log.Errorf("wait %d (%s) stopped timeout, force kill", p.Pid, p.Type)
+	p.killHard()
+
+	return nil
+}
+
+// killHard sends SIGKILL directly to the process, bypassing the control
+// pipe, for when the shim itself is unreachable or the graceful signal
+// didn't work in time.
+func (p *process) killHard() {
+	if proc, err := os.FindProcess(p.Pid); err == nil {
+		proc.Signal(os.Kill)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import "syscall"
+
+// backend names, stored in process.Backend and picked by callers of
+// newDefaultProcess.
+const (
+	backendExec = "exec"
+	backendOCI  = "oci"
+)
+
+// Executor is how a process actually gets run: fork/exec on the host,
+// or isolated inside a runc container. Every Executor still goes
+// through the reborn-shim wrapper for the ready handshake, control
+// pipe and exit-status bookkeeping; they differ in what they tell the
+// shim to exec and in the setup and cleanup around it (the oci
+// executor's bundle, rootfs mount and `runc delete`).
+type Executor interface {
+	Start(p *process) error
+	Stop(p *process) error
+	Signal(p *process, sig syscall.Signal) error
+	CheckAlive(p *process) (bool, error)
+	Wait(p *process) error
+}
+
+// executor resolves p.Backend to the Executor that should run it,
+// defaulting to the plain fork/exec executor for processes created
+// before Backend existed (empty string).
+func (p *process) executor() Executor {
+	switch p.Backend {
+	case backendOCI:
+		return ociExecutor{}
+	default:
+		return execExecutor{}
+	}
+}
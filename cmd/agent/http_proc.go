@@ -0,0 +1,101 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	log "github.com/ngaut/logging"
+)
+
+// procRegistry tracks the processes currently managed by this agent, so
+// the HTTP API can look one up by id without operators having to shell
+// into the host to send it a signal or resize its tty.
+var procRegistry = struct {
+	sync.RWMutex
+	byID map[string]*process
+}{byID: make(map[string]*process)}
+
+func registerProc(p *process) {
+	procRegistry.Lock()
+	procRegistry.byID[p.ID] = p
+	procRegistry.Unlock()
+}
+
+func unregisterProc(p *process) {
+	procRegistry.Lock()
+	delete(procRegistry.byID, p.ID)
+	procRegistry.Unlock()
+}
+
+func findProc(id string) *process {
+	procRegistry.RLock()
+	defer procRegistry.RUnlock()
+	return procRegistry.byID[id]
+}
+
+func init() {
+	http.HandleFunc("/api/proc/signal/", handleProcSignal)
+	http.HandleFunc("/api/proc/resize/", handleProcResize)
+}
+
+// handleProcSignal handles POST /api/proc/signal/<id>?sig=<n>, delivering
+// signal n to the managed process with the given id over its control
+// pipe, e.g. so operators can `kill -USR1` a managed redis without
+// shelling into the host.
+func handleProcSignal(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/proc/signal/")
+
+	p := findProc(id)
+	if p == nil {
+		http.Error(w, "no such process", http.StatusNotFound)
+		return
+	}
+
+	sig, err := strconv.Atoi(r.URL.Query().Get("sig"))
+	if err != nil {
+		http.Error(w, "invalid sig", http.StatusBadRequest)
+		return
+	}
+
+	if err := p.Signal(syscall.Signal(sig)); err != nil {
+		log.Errorf("signal %s err %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleProcResize handles POST /api/proc/resize/<id>?cols=<n>&rows=<n>,
+// resizing the managed process' controlling tty, if it has one.
+func handleProcResize(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/proc/resize/")
+
+	p := findProc(id)
+	if p == nil {
+		http.Error(w, "no such process", http.StatusNotFound)
+		return
+	}
+
+	cols, err1 := strconv.Atoi(r.URL.Query().Get("cols"))
+	rows, err2 := strconv.Atoi(r.URL.Query().Get("rows"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid cols/rows", http.StatusBadRequest)
+		return
+	}
+
+	if err := p.Resize(uint16(cols), uint16(rows)); err != nil {
+		log.Errorf("resize %s err %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
@@ -0,0 +1,202 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+
+	"github.com/juju/errors"
+	log "github.com/ngaut/logging"
+	"github.com/reborndb/go/io/ioutils"
+)
+
+// runcBin is the runc binary the oci executor execs to run a process
+// inside a container. Like shimBin, it's expected to be on PATH.
+var runcBin = "runc"
+
+// ociExecutor runs the managed process inside a runc container instead
+// of directly on the host. It still goes through the same reborn-shim
+// wrapper as execExecutor (runc itself is the "real command" the shim
+// forks/execs), so it gets the ready handshake, control pipe and exit
+// status bookkeeping for free.
+type ociExecutor struct {
+	execExecutor
+}
+
+// bundlePath is where we generate the OCI runtime bundle (config.json +
+// rootfs) for p, one per managed process.
+func (p *process) bundlePath() string {
+	return path.Join(dataDir, p.baseName())
+}
+
+func (e ociExecutor) Start(p *process) error {
+	bundle := p.bundlePath()
+	rootfs := path.Join(bundle, "rootfs")
+
+	if err := os.MkdirAll(rootfs, 0755); err != nil {
+		return errors.Trace(err)
+	}
+
+	image := p.Ctx["oci_image_path"]
+	if image == "" {
+		return errors.Errorf("start %s (oci): ctx[oci_image_path] is required", p.Type)
+	}
+
+	if err := mountBind(image, rootfs); err != nil {
+		return errors.Trace(err)
+	}
+
+	spec := newOCISpec(p, rootfs)
+	data, err := json.MarshalIndent(spec, "", "\t")
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := ioutils.WriteFileAtomic(path.Join(bundle, "config.json"), data, 0644); err != nil {
+		return errors.Trace(err)
+	}
+
+	return errors.Trace(p.startShim(runcBin, []string{"run", "-b", bundle, p.baseName()}))
+}
+
+func (e ociExecutor) Stop(p *process) error {
+	err := e.execExecutor.Stop(p)
+
+	if out, derr := exec.Command(runcBin, "delete", "-f", p.baseName()).CombinedOutput(); derr != nil {
+		log.Warningf("runc delete %s err %v, out %s", p.baseName(), derr, out)
+	}
+
+	if uerr := mountUnbind(path.Join(p.bundlePath(), "rootfs")); uerr != nil {
+		// rootfs is still a live bind mount of the operator-configured
+		// oci_image_path. Removing the bundle through a mount we failed
+		// to take down would recurse into that shared image and delete
+		// it out from under every other process using it, so bail out
+		// loudly instead and leave the bundle (and its mount) in place
+		// for an operator to clean up by hand.
+		log.Errorf("unmount rootfs for %s err %v, leaving bundle %s in place instead of deleting through a live mount", p.baseName(), uerr, p.bundlePath())
+		return errors.Trace(err)
+	}
+
+	os.RemoveAll(p.bundlePath())
+
+	return errors.Trace(err)
+}
+
+// ociSpec is a minimal subset of the OCI runtime spec, just enough to
+// run redis/qdb/proxy inside runc with the resource limits we already
+// track in process.Ctx, each instance isolated in its own mount/pid/
+// ipc/uts namespaces.
+type ociSpec struct {
+	Version string        `json:"ociVersion"`
+	Process ociProcess    `json:"process"`
+	Root    ociRoot       `json:"root"`
+	Mounts  []ociMount    `json:"mounts,omitempty"`
+	Linux   *ociSpecLinux `json:"linux,omitempty"`
+}
+
+type ociProcess struct {
+	Terminal bool     `json:"terminal"`
+	Args     []string `json:"args"`
+	Cwd      string   `json:"cwd"`
+}
+
+type ociRoot struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+type ociMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type ociSpecLinux struct {
+	Namespaces []ociNamespace `json:"namespaces,omitempty"`
+	Resources  *ociResources  `json:"resources,omitempty"`
+}
+
+type ociNamespace struct {
+	Type string `json:"type"`
+}
+
+type ociResources struct {
+	Memory *ociMemory `json:"memory,omitempty"`
+}
+
+type ociMemory struct {
+	Limit int64 `json:"limit"`
+}
+
+// ociDataMountDest is the fixed in-container path p.Ctx["oci_data_path"]
+// (when set) is bind-mounted to, giving redis/qdb somewhere writable to
+// put their dump/AOF/data files despite the read-only image rootfs.
+const ociDataMountDest = "/data"
+
+// defaultOCIMounts are the standard mounts every container needs so
+// /proc and /dev behave as expected inside the namespaces newOCISpec
+// sets up, mirroring runc's own default example spec.
+func defaultOCIMounts() []ociMount {
+	return []ociMount{
+		{Destination: "/proc", Type: "proc", Source: "proc"},
+		{Destination: "/dev", Type: "tmpfs", Source: "tmpfs",
+			Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+		{Destination: "/dev/pts", Type: "devpts", Source: "devpts",
+			Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620"}},
+		{Destination: "/dev/shm", Type: "tmpfs", Source: "shm",
+			Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+		{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue",
+			Options: []string{"nosuid", "noexec", "nodev"}},
+	}
+}
+
+func newOCISpec(p *process, rootfs string) *ociSpec {
+	mounts := defaultOCIMounts()
+	if dataPath := p.Ctx["oci_data_path"]; dataPath != "" {
+		// per-instance writable dir for dump/AOF/data files; rootfs
+		// itself is read-only (see mountBind) and shared across every
+		// instance using the same oci_image_path.
+		mounts = append(mounts, ociMount{
+			Destination: ociDataMountDest,
+			Type:        "bind",
+			Source:      dataPath,
+			Options:     []string{"rbind", "rw"},
+		})
+	}
+
+	spec := &ociSpec{
+		Version: "1.0.1",
+		Process: ociProcess{
+			Terminal: p.Ctx["tty"] == "1",
+			Args:     append([]string{p.Cmd}, p.Args...),
+			Cwd:      "/",
+		},
+		// rootfs is a read-only bind mount of the operator-configured,
+		// shared oci_image_path (see mountBind), so give each instance
+		// its own mount/pid/ipc/uts namespaces instead of sharing the
+		// host's: without that, two processes pointed at the same image
+		// would see each other's /proc and pids.
+		Root:   ociRoot{Path: rootfs, Readonly: true},
+		Mounts: mounts,
+		Linux: &ociSpecLinux{
+			Namespaces: []ociNamespace{
+				{Type: "mount"},
+				{Type: "pid"},
+				{Type: "ipc"},
+				{Type: "uts"},
+			},
+		},
+	}
+
+	if limit, err := strconv.ParseInt(p.Ctx["mem_limit"], 10, 64); err == nil && limit > 0 {
+		spec.Linux.Resources = &ociResources{Memory: &ociMemory{Limit: limit}}
+	}
+
+	return spec
+}
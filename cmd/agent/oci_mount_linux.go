@@ -0,0 +1,33 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// mountBind bind-mounts src (the configured image path) onto dst (the
+// bundle's rootfs dir) so runc can use it as the container root without
+// us having to copy the image around. The image path is shared across
+// every instance that uses it, so the mount is remounted read-only
+// right after the bind (the kernel ignores MS_RDONLY on the initial
+// MS_BIND call) to keep one instance from writing into, or corrupting,
+// another instance's view of the same image.
+func mountBind(src, dst string) error {
+	if err := syscall.Mount(src, dst, "", syscall.MS_BIND, ""); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := syscall.Mount(src, dst, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		return errors.Trace(err)
+	}
+
+	return nil
+}
+
+func mountUnbind(dst string) error {
+	return errors.Trace(syscall.Unmount(dst, 0))
+}
@@ -0,0 +1,19 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import "github.com/juju/errors"
+
+// the oci executor (runc) is linux-only, bind mounts included.
+
+func mountBind(src, dst string) error {
+	return errors.Errorf("oci executor is not supported on this platform")
+}
+
+func mountUnbind(dst string) error {
+	return errors.Errorf("oci executor is not supported on this platform")
+}
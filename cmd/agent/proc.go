@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"strconv"
 	"strings"
@@ -17,10 +16,10 @@ import (
 	"time"
 
 	"github.com/juju/errors"
-	"github.com/mitchellh/go-ps"
 	log "github.com/ngaut/logging"
 	"github.com/nu7hatch/gouuid"
 	"github.com/reborndb/go/io/ioutils"
+	"github.com/reborndb/reborn/pkg/events"
 )
 
 func genProcID() string {
@@ -43,6 +42,13 @@ type process struct {
 	// so we don't save it in data file.
 	Pid int `json:"-"`
 
+	// StartTime is the child's start time fingerprint, as reported by
+	// the shim right after fork/exec (see system.ReadStartTime). We
+	// persist it so checkAlive can still tell a live process from a
+	// pid that's been recycled by some other program after an agent
+	// restart, when we haven't gone through start() to refresh it.
+	StartTime uint64 `json:"start_time"`
+
 	// for start process, use cmd and args
 	Cmd  string   `json:"name"`
 	Args []string `json:"args"`
@@ -50,13 +56,31 @@ type process struct {
 	// for specail use
 	Ctx map[string]string `json:"ctx"`
 
+	// StartTimeout bounds how long start() waits for the shim to report
+	// readiness. Zero means defaultStartTimeout.
+	StartTimeout time.Duration `json:"-"`
+
+	// GraceSignal is sent first when stopping the process, giving it a
+	// chance to shut down cleanly. Zero means syscall.SIGTERM.
+	GraceSignal syscall.Signal `json:"-"`
+
+	// GracePeriod bounds how long stop() waits after GraceSignal before
+	// escalating to SIGKILL. Zero means defaultGracePeriod.
+	GracePeriod time.Duration `json:"-"`
+
+	// Backend selects the Executor used to run this process: "exec"
+	// (the default, plain fork/exec via the shim) or "oci" (runc).
+	// Empty is treated as "exec", so data files saved before Backend
+	// existed keep working.
+	Backend string `json:"backend"`
+
 	postStartFunc func(p *process) error
 
 	// if not nil, we will use this func to stop process
 	stopFunc func(p *process) error
 }
 
-func newDefaultProcess(cmd string, tp string) *process {
+func newDefaultProcess(cmd string, tp string, backend string) *process {
 	id := genProcID()
 	p := new(process)
 
@@ -64,6 +88,7 @@ func newDefaultProcess(cmd string, tp string) *process {
 	p.Cmd = cmd
 	p.Type = tp
 	p.Ctx = make(map[string]string)
+	p.Backend = backend
 
 	return p
 }
@@ -96,6 +121,15 @@ func loadProcess(dataPath string) (*process, error) {
 		return nil, errors.Trace(err)
 	}
 
+	// the shim has been waitpid'ing this process the whole time we were
+	// down, so ask it for the authoritative state instead of guessing
+	// from pid liveness alone.
+	if err := p.reconnectShim(); err != nil {
+		log.Warningf("reconnect shim for %s err %v", p.baseName(), err)
+	}
+
+	registerProc(p)
+
 	return p, nil
 }
 
@@ -113,28 +147,7 @@ func (p *process) addCmdArgs(args ...string) {
 }
 
 func (p *process) start() error {
-	c := exec.Command(p.Cmd, p.Args...)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-
-	if err := c.Start(); err != nil {
-		return errors.Trace(err)
-	}
-
-	go func() {
-		// use another goroutine to wait process over
-		// we don't handle anything here, because we will
-		// check process alive in a checker totally.
-		c.Wait()
-	}()
-
-	// wait some time
-	log.Infof("wait 3 seonds for %s starts ok", p.Type)
-	time.Sleep(3 * time.Second)
-
-	// we must read pid from pid file
-	var err error
-	if p.Pid, err = p.readPid(); err != nil {
+	if err := p.executor().Start(p); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -146,11 +159,21 @@ func (p *process) start() error {
 
 	if p.postStartFunc != nil {
 		if err := p.postStartFunc(p); err != nil {
-			log.Errorf("post start %d (%s) err %v", p.Pid, p.Type, err)
+			log.Errorf("post start %d (%s) err %v, stopping it", p.Pid, p.Type, err)
+			// the shim is already supervising the child at this point;
+			// if we don't stop it here it keeps running with no data
+			// file and is never registered, so neither
+			// needRestart() nor the checker loop can ever see it again.
+			if serr := p.executor().Stop(p); serr != nil {
+				log.Errorf("stop %d (%s) after failed post start err %v", p.Pid, p.Type, serr)
+			}
 			return errors.Trace(err)
 		}
 	}
 
+	registerProc(p)
+	postEvent(newProcEvent(events.TypeProcessStarted, p))
+
 	return errors.Trace(p.save())
 }
 
@@ -182,20 +205,7 @@ func (p *process) baseName() string {
 }
 
 func (p *process) checkAlive() (bool, error) {
-	proc, err := ps.FindProcess(p.Pid)
-	if err != nil {
-		return false, errors.Trace(err)
-	} else if proc == nil {
-		// proc is not alive
-		return false, nil
-	} else {
-		if strings.Contains(proc.Executable(), p.Cmd) {
-			return true, nil
-		} else {
-			log.Warningf("pid %d exits, but exeutable name is %s, not %s", p.Pid, proc.Executable(), p.Cmd)
-			return false, nil
-		}
-	}
+	return p.executor().CheckAlive(p)
 }
 
 func isFileExist(name string) bool {
@@ -211,50 +221,26 @@ func (p *process) needRestart() bool {
 	return isFileExist(p.pidPath())
 }
 
+// restart is what the checker loop should call instead of start()
+// when needRestart() says a process died unexpectedly: it posts
+// TypeProcessRestarted on success, on top of the TypeProcessStarted
+// that start() always posts.
+func (p *process) restart() error {
+	if err := p.start(); err != nil {
+		return errors.Trace(err)
+	}
+
+	postEvent(newProcEvent(events.TypeProcessRestarted, p))
+	return nil
+}
+
 func (p *process) clear() {
+	unregisterProc(p)
 	os.Remove(p.pidPath())
 	os.Remove(p.dataPath())
+	os.Remove(p.statePath())
 }
 
 func (p *process) stop() error {
-	b, err := p.checkAlive()
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	defer p.clear()
-
-	if !b {
-		return nil
-	} else {
-		if proc, err := os.FindProcess(p.Pid); err != nil {
-			return errors.Trace(err)
-		} else {
-			if p.stopFunc != nil {
-				if err := p.stopFunc(p); err != nil {
-					log.Errorf("stop %d (%s) err %v, send kill signal", p.Pid, p.Type, err)
-					proc.Signal(syscall.SIGTERM)
-					proc.Signal(os.Kill)
-				}
-			} else {
-				proc.Signal(syscall.SIGTERM)
-				proc.Signal(os.Kill)
-			}
-
-			ch := make(chan struct{}, 1)
-			go func(ch chan struct{}) {
-				proc.Wait()
-				ch <- struct{}{}
-			}(ch)
-
-			select {
-			case <-ch:
-			case <-time.After(5 * time.Minute):
-				proc.Kill()
-				log.Errorf("wait %d (%s)stopped timeout, force kill", p.Pid, p.Type)
-			}
-
-			return nil
-		}
-	}
+	return p.executor().Stop(p)
 }
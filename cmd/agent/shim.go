@@ -0,0 +1,268 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/ngaut/logging"
+	"github.com/reborndb/reborn/pkg/events"
+	"github.com/reborndb/reborn/pkg/shim"
+)
+
+// defaultStartTimeout bounds how long process.start() will block waiting
+// for the shim to report readiness, replacing the old fixed 3 second
+// sleep. Callers that need a different bound can set process.StartTimeout
+// before calling start().
+const defaultStartTimeout = 30 * time.Second
+
+// defaultGracePeriod bounds how long stop() waits after the graceful
+// signal before escalating to SIGKILL, replacing the old hard-coded
+// 5 minute timeout. Callers that need a different bound can set
+// process.GracePeriod before calling stop().
+const defaultGracePeriod = 30 * time.Second
+
+// readyPollInterval is how often waitReady retries a non-blocking read
+// of the ready FIFO while waiting for the shim to become its writer.
+const readyPollInterval = 50 * time.Millisecond
+
+// shimBin is the reborn-shim binary we exec to supervise every managed
+// process. It's expected to be on PATH next to the agent binary; we
+// don't hard-code an absolute path so packaging/deploy can place it
+// wherever they like.
+var shimBin = "reborn-shim"
+
+func (p *process) readyPath() string {
+	return path.Join(dataDir, fmt.Sprintf("%s_%s.ready", p.Type, p.ID))
+}
+
+func (p *process) statePath() string {
+	return path.Join(dataDir, fmt.Sprintf("%s_%s.state", p.Type, p.ID))
+}
+
+func (p *process) ctlSockPath() string {
+	return path.Join(dataDir, fmt.Sprintf("%s_%s.csock", p.Type, p.ID))
+}
+
+// startShim forks the reborn-shim wrapper, which in turn forks/execs
+// execCmd/execArgs (the real command for the exec executor, or `runc
+// run ...` for the oci executor). It blocks until the shim reports
+// readiness over the ready FIFO (or the command exits/times out
+// first), then fills in p.Pid and p.StartTime from the shim's state
+// file.
+func (p *process) startShim(execCmd string, execArgs []string) error {
+	os.Remove(p.readyPath())
+	if err := syscall.Mkfifo(p.readyPath(), 0600); err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(p.readyPath())
+
+	readyCh := make(chan string, 1)
+	cancelCh := make(chan struct{})
+	defer close(cancelCh)
+	go p.waitReady(readyCh, cancelCh)
+
+	shimArgs := []string{
+		"-data-dir", dataDir,
+		"-type", p.Type,
+		"-id", p.ID,
+	}
+	if p.Ctx["tty"] == "1" {
+		shimArgs = append(shimArgs, "-tty")
+	}
+	if cgroupMem := p.Ctx["cgroup_mem_path"]; cgroupMem != "" {
+		shimArgs = append(shimArgs, "-cgroup-mem", cgroupMem)
+	}
+	args := append(append(shimArgs, "--", execCmd), execArgs...)
+
+	c := exec.Command(shimBin, args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	// detach from the agent's process group so the shim (and the real
+	// child it owns) survives an agent restart.
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := c.Start(); err != nil {
+		return errors.Trace(err)
+	}
+	// we don't wait on the shim itself, it's meant to outlive us.
+	go c.Wait()
+
+	timeout := p.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+
+	select {
+	case line := <-readyCh:
+		if strings.HasPrefix(line, "error ") {
+			return errors.Errorf("start %s failed: %s", p.Type, strings.TrimPrefix(line, "error "))
+		} else if line != shim.ReadyMsg {
+			return errors.Errorf("start %s failed: unexpected ready message %q", p.Type, line)
+		}
+	case <-time.After(timeout):
+		return errors.Errorf("start %s timed out after %s waiting for shim", p.Type, timeout)
+	}
+
+	st, err := p.readState()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	p.Pid = st.Pid
+	p.StartTime = st.StartTime
+	return nil
+}
+
+// waitReady opens the ready FIFO for reading and sends the trimmed
+// readiness line (or an "error ..." line) to readyCh once the shim
+// writes to it. The FIFO is opened O_NONBLOCK and polled with plain
+// reads instead of the old blocking open(2), so a closed cancelCh
+// (startShim giving up on a Start error or readiness timeout) makes
+// this goroutine return instead of leaking forever in a blocking
+// open() that nothing will ever unblock.
+func (p *process) waitReady(readyCh chan<- string, cancelCh <-chan struct{}) {
+	fd, err := syscall.Open(p.readyPath(), syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		readyCh <- "error " + err.Error()
+		return
+	}
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 256)
+	for {
+		select {
+		case <-cancelCh:
+			return
+		default:
+		}
+
+		n, err := syscall.Read(fd, buf)
+		switch {
+		case err == syscall.EAGAIN:
+			time.Sleep(readyPollInterval)
+			continue
+		case err != nil:
+			readyCh <- "error " + err.Error()
+			return
+		case n == 0:
+			// no writer has connected yet (or one connected and closed
+			// without writing); keep polling until cancelled.
+			time.Sleep(readyPollInterval)
+			continue
+		}
+
+		readyCh <- strings.TrimSpace(string(buf[:n]))
+		return
+	}
+}
+
+func (p *process) readState() (shim.State, error) {
+	var st shim.State
+
+	data, err := ioutil.ReadFile(p.statePath())
+	if err != nil {
+		return st, errors.Trace(err)
+	}
+
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, errors.Trace(err)
+	}
+
+	return st, nil
+}
+
+// dialShim sends req to the process' shim control socket and returns its
+// response. It's used both for runtime control (Signal/Resize/stop) and,
+// on agent restart, to recover the exit status of a process that may
+// have already exited while we were down.
+func (p *process) dialShim(req shim.Request) (shim.Response, error) {
+	var resp shim.Response
+
+	conn, err := net.DialTimeout("unix", p.ctlSockPath(), 5*time.Second)
+	if err != nil {
+		return resp, errors.Trace(err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(&req); err != nil {
+		return resp, errors.Trace(err)
+	}
+
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, errors.Trace(err)
+	}
+
+	if resp.Err != "" {
+		return resp, errors.New(resp.Err)
+	}
+
+	return resp, nil
+}
+
+// reconnectShim asks the shim for the process' last known state. It is
+// used by loadProcess after an agent restart so we don't have to guess
+// liveness from pid existence alone: the shim has been waitpid'ing the
+// child the whole time we were down.
+func (p *process) reconnectShim() error {
+	resp, err := p.dialShim(shim.Request{Op: shim.OpStatus})
+	if err != nil {
+		// the shim may already be gone if the process exited long ago
+		// and its grace period elapsed; fall back to the state file.
+		log.Warningf("reconnect shim for %s err %v, falling back to state file", p.baseName(), err)
+
+		st, err := p.readState()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		p.applyState(st)
+		return nil
+	}
+
+	p.applyState(resp.State)
+	return nil
+}
+
+// Signal asks the shim to deliver sig to the managed child over the
+// control socket, rather than the agent signalling it directly, so it
+// keeps working even if the agent doesn't share the child's pid
+// namespace (e.g. the oci executor added later).
+func (p *process) Signal(sig syscall.Signal) error {
+	_, err := p.dialShim(shim.Request{Op: shim.OpSignal, Arg1: uint32(sig)})
+	return errors.Trace(err)
+}
+
+// Resize resizes the managed child's controlling tty to cols x rows. It
+// only has an effect if the process was started with a tty (see
+// process.Ctx["tty"]).
+func (p *process) Resize(cols, rows uint16) error {
+	_, err := p.dialShim(shim.Request{Op: shim.OpResize, Arg1: uint32(cols), Arg2: uint32(rows)})
+	return errors.Trace(err)
+}
+
+func (p *process) applyState(st shim.State) {
+	p.Pid = st.Pid
+	p.StartTime = st.StartTime
+	if st.Exited {
+		log.Warningf("%s (pid %d) already exited while agent was down, exit code %d signal %d",
+			p.baseName(), st.Pid, st.ExitCode, st.Signal)
+
+		ev := newProcEvent(events.TypeProcessExited, p)
+		ev.ExitCode, ev.Signal, ev.StartTime = st.ExitCode, st.Signal, st.StartTime
+		if st.OOMKilled {
+			ev.Type = events.TypeProcessOOM
+		}
+		postEvent(ev)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+// Command reborn-shim is a thin wrapper process spawned by the reborn
+// agent for every managed process (proxy, redis, qdb, ...). It does the
+// actual fork/exec of the real binary, writes the pid/state files, and
+// reports readiness to the agent over a FIFO. It then stays around and
+// owns the waitpid call, so the agent can be restarted without losing
+// the child's exit status.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/juju/errors"
+	"github.com/kr/pty"
+	log "github.com/ngaut/logging"
+	"github.com/reborndb/go/io/ioutils"
+	"github.com/reborndb/reborn/pkg/shim"
+	"github.com/reborndb/reborn/pkg/system"
+)
+
+var (
+	dataDir   = flag.String("data-dir", "", "agent data dir, shares pid/state/ready/ctl file naming with the agent")
+	procType  = flag.String("type", "", "managed process type, e.g proxy, redis, qdb")
+	procID    = flag.String("id", "", "managed process id")
+	useTTY    = flag.Bool("tty", false, "run the child under a pty so resize/OpResize work")
+	cgroupMem = flag.String("cgroup-mem", "", "cgroup memory controller path to check for an oom kill when the child exits, if set")
+)
+
+// childIO tracks whatever we need to service control-socket requests
+// against the running child: either a pty master (tty mode) or a plain
+// stdin pipe (non-tty mode).
+type childIO struct {
+	ptmx  *os.File
+	stdin io.WriteCloser
+}
+
+func (cio *childIO) closeStdin() {
+	if cio.ptmx != nil {
+		cio.ptmx.Close()
+	} else if cio.stdin != nil {
+		cio.stdin.Close()
+	}
+}
+
+// winsize mirrors the kernel's struct winsize for the TIOCSWINSZ ioctl.
+type winsize struct {
+	Rows, Cols, Xpixel, Ypixel uint16
+}
+
+func (cio *childIO) resize(cols, rows uint16) error {
+	if cio.ptmx == nil {
+		return errors.New("resize not supported for non-tty children")
+	}
+
+	ws := winsize{Rows: rows, Cols: cols}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, cio.ptmx.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return errors.Trace(errno)
+	}
+	return nil
+}
+
+func basePath(suffix string) string {
+	return path.Join(*dataDir, *procType+"_"+*procID+suffix)
+}
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		log.Fatalf("reborn-shim: no command to run")
+	}
+
+	readyPath := basePath(".ready")
+	pidPath := basePath(".pid")
+	statePath := basePath(".state")
+	ctlSockPath := basePath(".csock")
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	ready, err := os.OpenFile(readyPath, os.O_WRONLY, 0)
+	if err != nil {
+		log.Fatalf("reborn-shim: open ready pipe %s err %v", readyPath, err)
+	}
+
+	cio := &childIO{}
+	if *useTTY {
+		cio.ptmx, err = pty.Start(c)
+	} else {
+		if cio.stdin, err = c.StdinPipe(); err == nil {
+			err = c.Start()
+		}
+	}
+	if err != nil {
+		reportNotReady(ready, err)
+		os.Exit(1)
+	}
+
+	pid := c.Process.Pid
+	if err := ioutils.WriteFileAtomic(pidPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		log.Fatalf("reborn-shim: write pid file err %v", err)
+	}
+
+	startTime, err := system.ReadStartTime(pid)
+	if err != nil {
+		log.Warningf("reborn-shim: read start time for pid %d err %v", pid, err)
+	}
+
+	st := shim.State{Pid: pid, StartTime: startTime, StartedAt: time.Now()}
+	if err := saveState(statePath, st); err != nil {
+		log.Fatalf("reborn-shim: write state file err %v", err)
+	}
+
+	if _, err := ready.Write([]byte(shim.ReadyMsg + "\n")); err != nil {
+		log.Errorf("reborn-shim: signal ready err %v", err)
+	}
+	ready.Close()
+
+	ln, err := net.Listen("unix", ctlSockPath)
+	if err != nil {
+		log.Errorf("reborn-shim: listen control socket err %v", err)
+	} else {
+		go serveControl(ln, c, cio, statePath)
+	}
+
+	err = c.Wait()
+
+	st.Exited = true
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			st.ExitCode = ws.ExitStatus()
+			if ws.Signaled() {
+				st.Signal = int(ws.Signal())
+				if ws.Signal() == syscall.SIGKILL && *cgroupMem != "" {
+					st.OOMKilled = wasOOMKilled(*cgroupMem)
+				}
+			}
+		}
+	}
+
+	if err := saveState(statePath, st); err != nil {
+		log.Errorf("reborn-shim: write exit state err %v", err)
+	}
+
+	if ln != nil {
+		// keep the control socket alive for a while so the agent can
+		// reconnect and collect the final exit status even if it was
+		// restarted right as the child exited.
+		time.Sleep(5 * time.Second)
+		ln.Close()
+		os.Remove(ctlSockPath)
+	}
+}
+
+// wasOOMKilled does a best-effort check of whether the kernel's OOM
+// killer fired inside cgroupMem, the memory controller's cgroup path
+// for this process. It understands both the cgroup v2 "memory.events"
+// oom_kill counter and the cgroup v1 "memory.oom_control" under_oom
+// flag.
+func wasOOMKilled(cgroupMem string) bool {
+	if data, err := ioutil.ReadFile(path.Join(cgroupMem, "memory.events")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "oom_kill" {
+				if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+					return true
+				}
+			}
+		}
+	}
+
+	if data, err := ioutil.ReadFile(path.Join(cgroupMem, "memory.oom_control")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "under_oom 1" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func reportNotReady(ready *os.File, err error) {
+	ready.Write([]byte("error " + err.Error() + "\n"))
+	ready.Close()
+}
+
+func saveState(statePath string, st shim.State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutils.WriteFileAtomic(statePath, data, 0644))
+}
+
+func serveControl(ln net.Listener, c *exec.Cmd, cio *childIO, statePath string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go handleControl(conn, c, cio, statePath)
+	}
+}
+
+func handleControl(conn net.Conn, c *exec.Cmd, cio *childIO, statePath string) {
+	defer conn.Close()
+
+	var req shim.Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := shim.Response{}
+
+	switch req.Op {
+	case shim.OpStatus:
+		data, err := ioutil.ReadFile(statePath)
+		if err != nil {
+			resp.Err = err.Error()
+		} else if err := json.Unmarshal(data, &resp.State); err != nil {
+			resp.Err = err.Error()
+		}
+	case shim.OpCloseStdin:
+		cio.closeStdin()
+	case shim.OpSignal:
+		if c.Process != nil {
+			if err := c.Process.Signal(syscall.Signal(req.Arg1)); err != nil {
+				resp.Err = err.Error()
+			}
+		}
+	case shim.OpResize:
+		if err := cio.resize(uint16(req.Arg1), uint16(req.Arg2)); err != nil {
+			resp.Err = err.Error()
+		}
+	}
+
+	json.NewEncoder(conn).Encode(&resp)
+}
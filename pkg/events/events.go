@@ -0,0 +1,78 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+// Package events models the agent's process supervision as a stream of
+// lifecycle events, so dashboard and orchestration tools can get a
+// push-based view of proxy/redis/qdb health instead of polling the
+// agent's HTTP API.
+package events
+
+import "time"
+
+// Type identifies what happened to a managed process.
+type Type string
+
+const (
+	// TypeProcessStarted fires once start() has confirmed the process
+	// is alive and run its postStartFunc.
+	TypeProcessStarted Type = "process_started"
+
+	// TypeProcessExited fires whenever we learn a process is no longer
+	// running, whether it exited cleanly, was killed, or was stopped by
+	// an operator.
+	TypeProcessExited Type = "process_exited"
+
+	// TypeProcessOOM fires when the exit looks like it was caused by
+	// the kernel's OOM killer (only detected for processes confined to
+	// a cgroup with a memory limit).
+	TypeProcessOOM Type = "process_oom"
+
+	// TypeProcessRestarted fires after the agent has successfully
+	// restarted a process that exited unexpectedly.
+	TypeProcessRestarted Type = "process_restarted"
+)
+
+// Event is a single process lifecycle event. Not every field applies to
+// every Type; see the field comments below for which ones are set.
+type Event struct {
+	// ID is assigned by RingPoster so GET /events?since=<ID> can resume
+	// a stream without missing or repeating events. Posters that don't
+	// support querying (e.g. NATSPoster) leave it zero.
+	ID int64 `json:"id"`
+
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	Host     string `json:"host"`
+	ProcID   string `json:"proc_id"`
+	ProcType string `json:"proc_type"`
+	Pid      int    `json:"pid"`
+
+	// set on TypeProcessExited / TypeProcessOOM. FinishTime is the time
+	// the agent stopped the process; it is left zero for an exit the
+	// agent only learns about from the shim after its own restart.
+	ExitCode   int       `json:"exit_code,omitempty"`
+	Signal     int       `json:"signal,omitempty"`
+	StartTime  uint64    `json:"start_time,omitempty"`
+	FinishTime time.Time `json:"finish_time,omitempty"`
+}
+
+// Poster publishes Events somewhere: an in-memory ring buffer, NATS,
+// or anything else an operator wants to plug in (Kafka, etcd, ...).
+type Poster interface {
+	Post(ev Event) error
+}
+
+// MultiPoster fans an Event out to every Poster in it. Post returns the
+// first error encountered, after still attempting every poster.
+type MultiPoster []Poster
+
+func (m MultiPoster) Post(ev Event) error {
+	var firstErr error
+	for _, p := range m {
+		if err := p.Post(ev); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,35 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/nats-io/nats"
+)
+
+// NATSPoster publishes events to a NATS subject of the form
+// "reborn.agent.<host>.process.<type>", so orchestration tools can
+// subscribe to one host, one process type, or everything with a
+// wildcard subscription.
+type NATSPoster struct {
+	conn *nats.Conn
+	host string
+}
+
+func NewNATSPoster(conn *nats.Conn, host string) *NATSPoster {
+	return &NATSPoster{conn: conn, host: host}
+}
+
+func (n *NATSPoster) Post(ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	subject := fmt.Sprintf("reborn.agent.%s.process.%s", n.host, ev.ProcType)
+	return errors.Trace(n.conn.Publish(subject, data))
+}
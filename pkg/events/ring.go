@@ -0,0 +1,52 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package events
+
+import "sync"
+
+// RingPoster keeps the last capacity events in memory so the agent's
+// GET /events?since=<id> endpoint can serve them without a separate
+// event store. It's the default Poster when no NATS (or other) bus is
+// configured.
+type RingPoster struct {
+	mu     sync.Mutex
+	cap    int
+	nextID int64
+	buf    []Event
+}
+
+func NewRingPoster(capacity int) *RingPoster {
+	return &RingPoster{cap: capacity}
+}
+
+func (r *RingPoster) Post(ev Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	ev.ID = r.nextID
+
+	r.buf = append(r.buf, ev)
+	if len(r.buf) > r.cap {
+		r.buf = r.buf[len(r.buf)-r.cap:]
+	}
+
+	return nil
+}
+
+// Since returns every event with ID strictly greater than since, oldest
+// first. Events older than the ring's capacity are no longer available
+// and are silently omitted.
+func (r *RingPoster) Since(since int64) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Event, 0, len(r.buf))
+	for _, ev := range r.buf {
+		if ev.ID > since {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package events
+
+import "testing"
+
+func TestRingPosterSinceAssignsIncreasingIDs(t *testing.T) {
+	r := NewRingPoster(2)
+
+	for i := 0; i < 3; i++ {
+		if err := r.Post(Event{Type: TypeProcessStarted}); err != nil {
+			t.Fatalf("post %d err %v", i, err)
+		}
+	}
+
+	// capacity 2, 3 posted: only the last 2 (IDs 2 and 3) remain.
+	got := r.Since(0)
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].ID != 2 || got[1].ID != 3 {
+		t.Fatalf("got IDs %d, %d, want 2, 3", got[0].ID, got[1].ID)
+	}
+}
+
+func TestRingPosterSinceFiltersAndWrapsAround(t *testing.T) {
+	r := NewRingPoster(3)
+
+	for i := 0; i < 5; i++ {
+		if err := r.Post(Event{Type: TypeProcessExited}); err != nil {
+			t.Fatalf("post %d err %v", i, err)
+		}
+	}
+
+	// events 1 and 2 have fallen out of the ring; 3, 4, 5 remain.
+	got := r.Since(3)
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].ID != 4 || got[1].ID != 5 {
+		t.Fatalf("got IDs %d, %d, want 4, 5", got[0].ID, got[1].ID)
+	}
+
+	// since=0 (a resume from before the ring existed) should silently
+	// omit the events that have already fallen out, not error.
+	all := r.Since(0)
+	if len(all) != 3 {
+		t.Fatalf("got %d events, want 3", len(all))
+	}
+	if all[0].ID != 3 {
+		t.Fatalf("got oldest ID %d, want 3", all[0].ID)
+	}
+}
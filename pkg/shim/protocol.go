@@ -0,0 +1,67 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+// Package shim defines the on-disk state format and control-socket
+// protocol shared between the reborn agent and the reborn-shim wrapper
+// process it spawns for every managed process (proxy, redis, qdb, ...).
+//
+// The shim sits between the agent and the real child process, much like
+// containerd's shim: it owns the fork/exec and the waitpid call, so the
+// agent can restart without losing the child's exit status, and it
+// reports readiness to the agent over a FIFO instead of the agent
+// guessing with a fixed sleep.
+package shim
+
+import "time"
+
+// ReadyMsg is the single line written to the ready FIFO once the child
+// has been started. Anything else written means the child failed to
+// start and the remainder of the line is the error.
+const ReadyMsg = "running"
+
+// State is persisted atomically to "<type>_<id>.state" next to the pid
+// file, and is also what the shim hands back over the control socket
+// when asked for the child's status.
+type State struct {
+	Pid       int       `json:"pid"`
+	StartTime uint64    `json:"start_time"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Exited is true once the shim has reaped the child with waitpid.
+	Exited   bool `json:"exited"`
+	ExitCode int  `json:"exit_code"`
+	Signal   int  `json:"signal"`
+
+	// OOMKilled is best-effort: only set when the process was started
+	// with a cgroup memory path and the kernel's oom killer fired
+	// against it.
+	OOMKilled bool `json:"oom_killed"`
+}
+
+// Request is sent by the agent to the shim's control socket.
+type Request struct {
+	// Op is one of the Op* constants below.
+	Op uint8 `json:"op"`
+
+	// Arg1/Arg2 carry Op-specific arguments, e.g. signal number or
+	// terminal width/height for OpResize.
+	Arg1 uint32 `json:"arg1"`
+	Arg2 uint32 `json:"arg2"`
+}
+
+const (
+	// OpStatus asks the shim for the child's current/last State.
+	OpStatus uint8 = iota
+	// OpCloseStdin closes the child's stdin.
+	OpCloseStdin
+	// OpResize resizes the child's controlling tty to Arg1 x Arg2 (cols x rows).
+	OpResize
+	// OpSignal sends signal number Arg1 to the child.
+	OpSignal
+)
+
+// Response is the shim's reply to a Request.
+type Response struct {
+	Err   string `json:"err,omitempty"`
+	State State  `json:"state"`
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package shim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestJSONRoundTrip(t *testing.T) {
+	req := Request{Op: OpResize, Arg1: 80, Arg2: 24}
+
+	data, err := json.Marshal(&req)
+	if err != nil {
+		t.Fatalf("marshal err %v", err)
+	}
+
+	var got Request
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal err %v", err)
+	}
+
+	if got != req {
+		t.Fatalf("got %+v, want %+v", got, req)
+	}
+}
+
+func TestResponseJSONRoundTrip(t *testing.T) {
+	resp := Response{
+		State: State{
+			Pid:       1234,
+			StartTime: 56789,
+			Exited:    true,
+			ExitCode:  1,
+			Signal:    int(9),
+			OOMKilled: true,
+		},
+	}
+
+	data, err := json.Marshal(&resp)
+	if err != nil {
+		t.Fatalf("marshal err %v", err)
+	}
+
+	var got Response
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal err %v", err)
+	}
+
+	if got.Err != "" {
+		t.Fatalf("got Err %q, want empty", got.Err)
+	}
+	if got.State != resp.State {
+		t.Fatalf("got state %+v, want %+v", got.State, resp.State)
+	}
+}
+
+func TestResponseJSONRoundTripWithErr(t *testing.T) {
+	resp := Response{Err: "child already exited"}
+
+	data, err := json.Marshal(&resp)
+	if err != nil {
+		t.Fatalf("marshal err %v", err)
+	}
+
+	var got Response
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal err %v", err)
+	}
+
+	if got.Err != resp.Err {
+		t.Fatalf("got Err %q, want %q", got.Err, resp.Err)
+	}
+}
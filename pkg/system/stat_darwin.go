@@ -0,0 +1,22 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package system
+
+import (
+	"github.com/juju/errors"
+	"golang.org/x/sys/unix"
+)
+
+// readStartTime asks the kernel for pid's kinfo_proc via the
+// kern.proc.pid sysctl and returns its start time as microseconds since
+// the epoch.
+func readStartTime(pid int) (uint64, error) {
+	kp, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	tv := kp.Eproc.Tstart
+	return uint64(tv.Sec)*1e6 + uint64(tv.Usec), nil
+}
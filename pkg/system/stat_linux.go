@@ -0,0 +1,51 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// readStartTime reads field 22 (starttime) of /proc/<pid>/stat: the
+// number of clock ticks since boot the process started at.
+func readStartTime(pid int) (uint64, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	start, err := parseStatStartTime(data)
+	if err != nil {
+		return 0, errors.Errorf("unexpected /proc/%d/stat contents", pid)
+	}
+
+	return start, nil
+}
+
+// parseStatStartTime parses field 22 (starttime) out of the raw
+// contents of a /proc/<pid>/stat file. It's split out from
+// readStartTime so the parsing, including the parenthesized-comm edge
+// case, can be tested without a real /proc.
+func parseStatStartTime(data []byte) (uint64, error) {
+	// the comm field is parenthesized and may itself contain spaces or
+	// parens, so skip past its closing ')' before splitting on spaces.
+	i := strings.LastIndex(string(data), ")")
+	if i < 0 {
+		return 0, errors.Errorf("unexpected stat contents")
+	}
+
+	// fields[0] is now the state field (field 3 overall); starttime is
+	// field 22, i.e. index 22-3 = 19 here.
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 {
+		return 0, errors.Errorf("unexpected stat format")
+	}
+
+	return strconv.ParseUint(fields[19], 10, 64)
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package system
+
+import "testing"
+
+func TestParseStatStartTime(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want uint64
+		fail bool
+	}{
+		{
+			name: "normal comm",
+			data: "1234 (redis-server) S 1 1234 1234 0 -1 4194560 123 0 0 0 1 2 0 0 20 0 1 0 56789 123456789 1234 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 2 0 0 0 0 0\n",
+			want: 56789,
+		},
+		{
+			name: "comm contains spaces and parens",
+			data: "1234 (my (weird) proc name) S 1 1234 1234 0 -1 4194560 123 0 0 0 1 2 0 0 20 0 1 0 98765 123456789 1234 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 2 0 0 0 0 0\n",
+			want: 98765,
+		},
+		{
+			name: "no closing paren",
+			data: "1234 redis-server S 1\n",
+			fail: true,
+		},
+		{
+			name: "too few fields after comm",
+			data: "1234 (redis-server) S 1 2 3\n",
+			fail: true,
+		},
+	}
+
+	for _, c := range cases {
+		got, err := parseStatStartTime([]byte(c.data))
+		if c.fail {
+			if err == nil {
+				t.Errorf("%s: expected error, got start time %d", c.name, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
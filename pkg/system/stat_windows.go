@@ -0,0 +1,10 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+package system
+
+// readStartTime has no portable implementation on Windows; callers fall
+// back to the executable-name check instead.
+func readStartTime(pid int) (uint64, error) {
+	return 0, ErrUnsupported
+}
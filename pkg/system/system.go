@@ -0,0 +1,24 @@
+// Copyright 2015 Reborndb Org. All Rights Reserved.
+// Licensed under the MIT (MIT-LICENSE.txt) license.
+
+// Package system provides small, build-tagged OS helpers that the agent
+// needs and the standard library doesn't expose portably.
+package system
+
+import "github.com/juju/errors"
+
+// ErrUnsupported is returned by ReadStartTime on platforms where we have
+// no reliable way to read a process' start time (currently Windows).
+// Callers should fall back to a weaker liveness check in that case.
+var ErrUnsupported = errors.New("system: ReadStartTime not supported on this platform")
+
+// ReadStartTime returns an opaque fingerprint of when pid was started.
+// It is only meaningful for equality comparison against a value recorded
+// earlier for the same pid on the same machine: on Linux it's the raw
+// clock-ticks-since-boot from /proc/<pid>/stat, on Darwin/FreeBSD it's
+// derived from the kern.proc.pid sysctl's start timeval. Either way, if
+// the value for a given pid changes, the pid has been recycled by a
+// different process.
+func ReadStartTime(pid int) (uint64, error) {
+	return readStartTime(pid)
+}